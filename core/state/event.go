@@ -0,0 +1,58 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// Event records a single contract/runtime event, addressable by the
+// transaction that produced it and, within that transaction, by its
+// position. Topic/Data keep the original free-form payload used by the
+// NVM and native contracts; Topics are additionally indexed (in the block
+// EventBloom and under topic keys in eventsState) so that events can be
+// located with FilterEvents without scanning every entry in eventsState.
+// Address is matched by FilterEvents but is not indexed.
+type Event struct {
+	Topic string `json:"topic"`
+	Data  string `json:"data"`
+
+	// Address is the account that emitted the event. Empty for events that
+	// are not tied to a single contract.
+	Address byteutils.Hash `json:"address,omitempty"`
+
+	// Topics holds up to four indexed topic slots, following the same
+	// convention Ethereum uses for logs: Topics[0] is usually the event
+	// signature and Topics[1:] are indexed arguments. Each topic is also
+	// folded into the owning block's EventBloom.
+	Topics [][]byte `json:"topics,omitempty"`
+}
+
+// maxIndexedTopics bounds how many topics of an event are folded into the
+// block-level EventBloom, matching the four-topic convention above.
+const maxIndexedTopics = 4
+
+// indexedTopics returns the topics that participate in bloom filtering,
+// truncated to maxIndexedTopics.
+func (e *Event) indexedTopics() [][]byte {
+	if len(e.Topics) <= maxIndexedTopics {
+		return e.Topics
+	}
+	return e.Topics[:maxIndexedTopics]
+}
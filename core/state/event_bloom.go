@@ -0,0 +1,94 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"github.com/nebulasio/go-nebulas/crypto/hash"
+)
+
+// eventBloomBytes is the size of an EventBloom, 2048 bits.
+const eventBloomBytes = 256
+
+// eventBloomBits is the number of addressable bits in an EventBloom.
+const eventBloomBits = eventBloomBytes * 8
+
+// EventBloom is a 2048-bit Bloom filter over event topics. Every topic sets
+// three bits, each derived from a distinct 11-bit slice of the topic's
+// Sha3256 hash, so that a block (or a range of blocks, once OR-ed together)
+// can be tested for "definitely does not contain this topic" without
+// visiting eventsState at all.
+type EventBloom [eventBloomBytes]byte
+
+// bloomIndexes derives the three bit positions a topic sets/tests.
+func bloomIndexes(topic []byte) [3]uint {
+	sum := hash.Sha3256(topic)
+	var idx [3]uint
+	for i := 0; i < 3; i++ {
+		idx[i] = (uint(sum[2*i])<<8 | uint(sum[2*i+1])) % eventBloomBits
+	}
+	return idx
+}
+
+// Add sets the bits a topic maps to.
+func (b *EventBloom) Add(topic []byte) {
+	for _, i := range bloomIndexes(topic) {
+		b[i/8] |= 1 << (i % 8)
+	}
+}
+
+// Test reports whether topic may be present. A false return is a proof of
+// absence; a true return means the topic might be present and the caller
+// still needs to check the underlying events.
+func (b *EventBloom) Test(topic []byte) bool {
+	for _, i := range bloomIndexes(topic) {
+		if b[i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Or merges another bloom into b, used to fold per-tx blooms into the
+// per-block bloom, and per-block blooms into a range bloom.
+func (b *EventBloom) Or(other *EventBloom) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+// Bytes returns the bloom's on-disk representation.
+func (b *EventBloom) Bytes() []byte {
+	return b[:]
+}
+
+// BloomFromBytes restores an EventBloom previously produced by Bytes.
+func BloomFromBytes(data []byte) *EventBloom {
+	b := new(EventBloom)
+	copy(b[:], data)
+	return b
+}
+
+// NewEventBloom builds a bloom over a set of topics in one call.
+func NewEventBloom(topics ...[]byte) *EventBloom {
+	b := new(EventBloom)
+	for _, topic := range topics {
+		b.Add(topic)
+	}
+	return b
+}
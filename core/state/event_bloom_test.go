@@ -0,0 +1,56 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import "testing"
+
+func TestEventBloomAddAndTest(t *testing.T) {
+	bloom := NewEventBloom([]byte("transfer"), []byte("approve"))
+
+	if !bloom.Test([]byte("transfer")) {
+		t.Fatal("bloom should report transfer as possibly present")
+	}
+	if !bloom.Test([]byte("approve")) {
+		t.Fatal("bloom should report approve as possibly present")
+	}
+	if bloom.Test([]byte("mint")) {
+		t.Fatal("bloom should report mint as definitely absent")
+	}
+}
+
+func TestEventBloomOr(t *testing.T) {
+	a := NewEventBloom([]byte("transfer"))
+	b := NewEventBloom([]byte("approve"))
+
+	a.Or(b)
+
+	if !a.Test([]byte("transfer")) || !a.Test([]byte("approve")) {
+		t.Fatal("Or should fold both blooms' topics into a")
+	}
+}
+
+func TestEventBloomBytesRoundTrip(t *testing.T) {
+	original := NewEventBloom([]byte("transfer"))
+
+	restored := BloomFromBytes(original.Bytes())
+
+	if !restored.Test([]byte("transfer")) {
+		t.Fatal("restored bloom lost its topic")
+	}
+}
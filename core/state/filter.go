@@ -0,0 +1,175 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/nebulasio/go-nebulas/common/trie"
+	"github.com/nebulasio/go-nebulas/crypto/hash"
+	"github.com/nebulasio/go-nebulas/storage"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// topicIndexPrefix tags secondary eventsState entries that index an event
+// by topic, keeping them out of the txHash-prefixed range FetchEvents scans.
+var topicIndexPrefix = []byte{0xEF}
+
+// topicIndexKey builds the secondary key an event is additionally stored
+// under for a given topic, so FilterEvents can iterate by topic without
+// visiting every event in the trie.
+func topicIndexKey(topic []byte, primaryKey []byte) []byte {
+	key := append([]byte{}, topicIndexPrefix...)
+	key = append(key, hash.Sha3256(topic)...)
+	return append(key, primaryKey...)
+}
+
+// BlockEventsLookup resolves one block height to the data FilterEvents
+// needs to decide whether it is worth opening: the eventsState root
+// committed at that height, and the EventBloom recorded for it (nil if the
+// caller doesn't have one, in which case the block is always opened).
+type BlockEventsLookup func(height uint64) (eventsRoot byteutils.Hash, bloom *EventBloom, err error)
+
+// FilterEvents returns every event committed between fromBlock and toBlock
+// (inclusive) matching addresses and topics, modeled on eth_getLogs.
+// lookup resolves each height in the range to its committed events root and
+// bloom; a height whose bloom proves it contains none of the requested
+// topics is skipped without ever opening its eventsState trie, which is the
+// whole reason the bloom is maintained -- range queries need not scan every
+// block's events. An empty addresses (or topics) slice matches any address
+// (or topic).
+func (s *states) FilterEvents(fromBlock, toBlock uint64, addresses []byteutils.Hash, topics [][]byte, lookup BlockEventsLookup) ([]*Event, error) {
+	query := &FilterQuery{FromBlock: fromBlock, ToBlock: toBlock, Addresses: addresses, Topics: topics}
+
+	events := []*Event{}
+	for height := fromBlock; height <= toBlock; height++ {
+		eventsRoot, bloom, err := lookup(height)
+		if err != nil {
+			return nil, err
+		}
+		if bloom != nil && !bloomMayMatch(bloom, topics) {
+			continue
+		}
+
+		blockEvents, err := s.filterEventsAtRoot(eventsRoot, query)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, blockEvents...)
+	}
+	return events, nil
+}
+
+// bloomMayMatch reports whether a block's bloom leaves open the
+// possibility it contains at least one of topics. An empty topics list
+// always may match, since the query isn't topic-filtered.
+func bloomMayMatch(bloom *EventBloom, topics [][]byte) bool {
+	if len(topics) == 0 {
+		return true
+	}
+	for _, topic := range topics {
+		if bloom.Test(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterEventsAtRoot scans a single block's events trie. When topics are
+// given, the first one narrows the scan to its topic index instead of
+// visiting every event in the trie.
+func (s *states) filterEventsAtRoot(eventsRoot byteutils.Hash, query *FilterQuery) ([]*Event, error) {
+	eventsTrie, err := trie.NewTrie(eventsRoot, s.storage, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix byteutils.Hash
+	if len(query.Topics) > 0 {
+		prefix = topicIndexKey(query.Topics[0], nil)
+	}
+
+	iter, err := eventsTrie.Iterator(prefix)
+	if err != nil && err != storage.ErrKeyNotFound {
+		return nil, err
+	}
+	events := []*Event{}
+	if err == storage.ErrKeyNotFound {
+		return events, nil
+	}
+
+	exist, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	for exist {
+		event := new(Event)
+		if err := json.Unmarshal(iter.Value(), event); err != nil {
+			return nil, err
+		}
+		if query.matches(event) {
+			events = append(events, event)
+		}
+		exist, err = iter.Next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// FilterQuery describes a log filter, modeled on eth_getLogs.
+type FilterQuery struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []byteutils.Hash
+	Topics    [][]byte
+}
+
+func (q *FilterQuery) matches(event *Event) bool {
+	if len(q.Addresses) > 0 {
+		matched := false
+		for _, addr := range q.Addresses {
+			if bytes.Equal(addr, event.Address) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(q.Topics) == 0 {
+		return true
+	}
+	for _, topic := range q.Topics {
+		matched := false
+		for _, t := range event.indexedTopics() {
+			if bytes.Equal(t, topic) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
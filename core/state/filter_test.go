@@ -0,0 +1,64 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"testing"
+
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+func TestFilterQueryMatchesAddressAndTopic(t *testing.T) {
+	event := &Event{
+		Address: []byte("contract-a"),
+		Topics:  [][]byte{[]byte("transfer"), []byte("from-a")},
+	}
+
+	query := &FilterQuery{
+		Addresses: []byteutils.Hash{[]byte("contract-a")},
+		Topics:    [][]byte{[]byte("transfer")},
+	}
+	if !query.matches(event) {
+		t.Fatal("expected query to match event on address and topic")
+	}
+
+	wrongAddress := &FilterQuery{Addresses: []byteutils.Hash{[]byte("contract-b")}}
+	if wrongAddress.matches(event) {
+		t.Fatal("expected query to reject event from a different address")
+	}
+
+	missingTopic := &FilterQuery{Topics: [][]byte{[]byte("approve")}}
+	if missingTopic.matches(event) {
+		t.Fatal("expected query to reject event missing the requested topic")
+	}
+}
+
+func TestBloomMayMatch(t *testing.T) {
+	bloom := NewEventBloom([]byte("transfer"))
+
+	if !bloomMayMatch(bloom, nil) {
+		t.Fatal("an untopic-filtered query should always may-match")
+	}
+	if !bloomMayMatch(bloom, [][]byte{[]byte("transfer")}) {
+		t.Fatal("bloom should may-match a topic it was built from")
+	}
+	if bloomMayMatch(bloom, [][]byte{[]byte("approve")}) {
+		t.Fatal("bloom should rule out a topic it was never given")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"sync"
+
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// Executable is one transaction's worth of work ExecuteBlock schedules.
+// state has no notion of what a transaction actually does -- that lives in
+// core, which already depends on state and so cannot be depended on back
+// -- so core.Transaction is adapted to this interface at the call site
+// instead of being referenced here directly.
+type Executable interface {
+	// TxHash identifies this transaction for the MVCC scheduler's
+	// Prepare/CheckAndUpdate/Reset/Close calls, and is the same hash
+	// RecordEvent/ReplayEvent key a transaction's events by -- it must be
+	// the transaction's real hash, not its position in the block.
+	TxHash() byteutils.Hash
+
+	// Execute runs against txState, a TxWorldState already Prepare'd by the
+	// scheduler, and returns the receipt to surface for this transaction.
+	Execute(txState TxWorldState) (Receipt, error)
+}
+
+// Receipt is the opaque result of executing one Executable.
+type Receipt interface{}
+
+// ParallelExecutor runs a block's transactions using the MVCC scheduling
+// already built into WorldState.Prepare/CheckAndUpdate: every transaction
+// speculatively executes against its own Prepare'd snapshot on a worker
+// from a fixed pool, keyed by its own tx hash so the scheduler's txids line
+// up with the ones RecordEvent/ReplayEvent use. CheckAndUpdate only
+// validates -- WorldState.Close is what merges a transaction's effects into
+// the shared state, so a transaction never needs to be unwound after the
+// fact. A transaction only Closes once every lower-index (higher-priority)
+// transaction already has: if it conflicts with one that has not committed
+// yet, or it simply is not its turn yet even without a reported conflict,
+// the worker Resets it (a no-op on the shared state, since nothing merged)
+// and re-queues it to be re-validated later, against whatever committed in
+// the meantime. Workers always pick up the lowest-index pending transaction
+// first, so the commit order -- and therefore the result -- is identical to
+// running the block sequentially; only the amount of work that overlaps in
+// time changes.
+type ParallelExecutor struct {
+	ws      WorldState
+	workers int
+}
+
+// NewParallelExecutor builds an executor over ws using up to workers
+// concurrent goroutines. workers <= 0 is treated as 1 (sequential).
+func NewParallelExecutor(ws WorldState, workers int) *ParallelExecutor {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &ParallelExecutor{ws: ws, workers: workers}
+}
+
+// executorState is the mutable, lock-guarded scheduling state shared by the
+// worker pool for a single ExecuteBlock call.
+type executorState struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	pending     []int
+	committed   []bool
+	receipts    []Receipt
+	txids       []string
+	indexByTxid map[string]int
+	nextCommit  int
+	err         error
+}
+
+// ExecuteBlock runs txs to completion, re-validating any transaction that
+// conflicts with a still-uncommitted, higher-priority transaction or whose
+// commit turn simply has not arrived yet, and returns receipts in original
+// block order.
+func (pe *ParallelExecutor) ExecuteBlock(txs []Executable) ([]Receipt, error) {
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	es := &executorState{
+		pending:     make([]int, len(txs)),
+		committed:   make([]bool, len(txs)),
+		receipts:    make([]Receipt, len(txs)),
+		txids:       make([]string, len(txs)),
+		indexByTxid: make(map[string]int, len(txs)),
+	}
+	es.cond = sync.NewCond(&es.mu)
+	for i, tx := range txs {
+		es.pending[i] = i
+		txid := tx.TxHash().String()
+		es.txids[i] = txid
+		es.indexByTxid[txid] = i
+	}
+
+	workers := pe.workers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			pe.worker(es, txs)
+		}()
+	}
+	wg.Wait()
+
+	if es.err != nil {
+		return nil, es.err
+	}
+	return es.receipts, nil
+}
+
+func (pe *ParallelExecutor) worker(es *executorState, txs []Executable) {
+	for {
+		es.mu.Lock()
+		for len(es.pending) == 0 && es.nextCommit < len(txs) && es.err == nil {
+			es.cond.Wait()
+		}
+		if es.nextCommit >= len(txs) || es.err != nil {
+			es.mu.Unlock()
+			return
+		}
+		index := popLowestPriority(&es.pending)
+		es.mu.Unlock()
+
+		if err := pe.executeOne(es, txs, index); err != nil {
+			es.mu.Lock()
+			if es.err == nil {
+				es.err = err
+			}
+			es.mu.Unlock()
+			es.cond.Broadcast()
+			return
+		}
+	}
+}
+
+// popLowestPriority removes and returns the lowest (highest-priority) index
+// in pending. Always giving the lowest index first, rather than whichever
+// was queued most recently, is what keeps the schedule converging on
+// sequential order instead of routinely letting a lower-priority
+// transaction commit ahead of one it will go on to conflict with.
+func popLowestPriority(pending *[]int) int {
+	p := *pending
+	best := 0
+	for i := 1; i < len(p); i++ {
+		if p[i] < p[best] {
+			best = i
+		}
+	}
+	index := p[best]
+	p[best] = p[len(p)-1]
+	*pending = p[:len(p)-1]
+	return index
+}
+
+// executeOne speculatively runs a single transaction and either commits it
+// (recording its receipt in block order and waking any worker that might
+// now be unblocked) or resets it and puts it back on the pending queue to
+// be retried later. A transaction is reset not only when CheckAndUpdate
+// reports a conflict with a not-yet-committed higher-priority transaction,
+// but also whenever it is simply not its turn to commit yet (index !=
+// es.nextCommit): commits only ever happen in block order, so a
+// transaction is always re-validated against the latest state right before
+// it merges, and nothing ever has to unwind an already-merged commit.
+func (pe *ParallelExecutor) executeOne(es *executorState, txs []Executable, index int) error {
+	txid := es.txids[index]
+	txState, err := pe.ws.Prepare(txid)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := txs[index].Execute(txState)
+	if err != nil {
+		pe.ws.Reset(txid)
+		return err
+	}
+
+	dependencies, err := pe.ws.CheckAndUpdate(txid)
+	if err != nil {
+		return err
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if index != es.nextCommit || blockedOnHigherPriority(index, dependencies, es.committed, es.indexByTxid) {
+		if err := pe.ws.Reset(txid); err != nil {
+			return err
+		}
+		es.pending = append(es.pending, index)
+		es.cond.Broadcast()
+		return nil
+	}
+
+	if err := pe.ws.Close(txid); err != nil {
+		return err
+	}
+	es.committed[index] = true
+	es.receipts[index] = receipt
+	es.nextCommit++
+
+	es.cond.Broadcast()
+	return nil
+}
+
+// blockedOnHigherPriority reports whether index conflicts with a dependency
+// of strictly lower index (higher priority, since lower index always wins)
+// that has not committed yet. Dependencies are reported by the same txid
+// string CheckAndUpdate was called with, so indexByTxid maps them back to
+// block position.
+func blockedOnHigherPriority(index int, dependencies []interface{}, committed []bool, indexByTxid map[string]int) bool {
+	for _, dep := range dependencies {
+		depTxid, ok := dep.(string)
+		if !ok {
+			continue
+		}
+		depIndex, ok := indexByTxid[depTxid]
+		if !ok {
+			continue
+		}
+		if depIndex < index && !committed[depIndex] {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteBlock runs txs against ws using workers concurrent goroutines. It
+// is the convenience entry point most callers use instead of constructing
+// a ParallelExecutor directly.
+func (ws *worldState) ExecuteBlock(txs []Executable, workers int) ([]Receipt, error) {
+	return NewParallelExecutor(ws, workers).ExecuteBlock(txs)
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import "testing"
+
+func TestPopLowestPriority(t *testing.T) {
+	pending := []int{3, 1, 2}
+
+	index := popLowestPriority(&pending)
+
+	if index != 1 {
+		t.Fatalf("expected lowest index 1, got %d", index)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 remaining, got %d", len(pending))
+	}
+	for _, p := range pending {
+		if p == 1 {
+			t.Fatal("popped index should no longer be in pending")
+		}
+	}
+}
+
+func TestBlockedOnHigherPriority(t *testing.T) {
+	indexByTxid := map[string]int{"tx0": 0, "tx1": 1, "tx2": 2}
+	committed := []bool{true, false, false}
+
+	// index 2 depends on tx1 (lower index, not yet committed): blocked.
+	if !blockedOnHigherPriority(2, []interface{}{"tx1"}, committed, indexByTxid) {
+		t.Fatal("expected index 2 to be blocked on uncommitted higher-priority tx1")
+	}
+
+	// index 2 depends on tx0 (lower index, already committed): not blocked.
+	if blockedOnHigherPriority(2, []interface{}{"tx0"}, committed, indexByTxid) {
+		t.Fatal("did not expect index 2 to be blocked on already-committed tx0")
+	}
+
+	// index 0 depends on tx2 (higher index): priority already favors index 0.
+	if blockedOnHigherPriority(0, []interface{}{"tx2"}, committed, indexByTxid) {
+		t.Fatal("did not expect index 0 to be blocked on a lower-priority dependency")
+	}
+
+	// Dependencies reported with a non-string (or unknown) txid are ignored
+	// rather than blocking -- this is exactly the shape a stray int txid
+	// from a caller still mid-migration would take.
+	if blockedOnHigherPriority(2, []interface{}{1}, committed, indexByTxid) {
+		t.Fatal("a non-string dependency should never be treated as blocking")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"github.com/nebulasio/go-nebulas/consensus/pb"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// rootSet is a snapshot of the four trie roots a committed block is
+// addressable by, the same four values LoadAccountsRoot/LoadTxsRoot/
+// LoadEventsRoot/LoadConsensusRoot restore individually.
+type rootSet struct {
+	accounts  byteutils.Hash
+	txs       byteutils.Hash
+	events    byteutils.Hash
+	consensus *consensuspb.ConsensusRoot
+}
+
+// maxRootHistory bounds how many recent committed blocks' roots Prune is
+// willing to keep reachable; a keepBlocks at or above this leaves Prune with
+// nothing to drop, so the call is a no-op.
+const maxRootHistory = 4096
+
+// recordRoots appends the roots of a just-committed block to the history
+// Prune walks for reachability, trimming anything older than
+// maxRootHistory since those are never a valid keepBlocks target anyway.
+func (s *states) recordRoots() error {
+	accountsRoot, err := s.AccountsRoot()
+	if err != nil {
+		return err
+	}
+	txsRoot, err := s.TxsRoot()
+	if err != nil {
+		return err
+	}
+	eventsRoot, err := s.EventsRoot()
+	if err != nil {
+		return err
+	}
+	consensusRoot, err := s.ConsensusRoot()
+	if err != nil {
+		return err
+	}
+
+	s.rootHistory = append(s.rootHistory, rootSet{
+		accounts:  accountsRoot,
+		txs:       txsRoot,
+		events:    eventsRoot,
+		consensus: consensusRoot,
+	})
+	if len(s.rootHistory) > maxRootHistory {
+		s.rootHistory = s.rootHistory[len(s.rootHistory)-maxRootHistory:]
+	}
+	return nil
+}
+
+// Prune garbage-collects trie nodes that are not reachable from any of the
+// accounts/txs/events/consensus roots of the last keepBlocks committed
+// blocks. Nodes are reference-counted by the trie layer itself (each
+// Put/CopyTo bumps a node's refcount, each Prune walk decrements the nodes
+// of roots it drops); this just computes which roots are still retained and
+// hands each trie kind its own roots to sweep -- accounts, txs, events, and
+// consensus each live in a separate hash space, so a root from one kind
+// means nothing to another's Prune.
+//
+// Operators that want an archive node simply never call Prune; operators
+// that want a pruned node call it periodically with the window they can
+// afford to serve historical queries for.
+func (s *states) Prune(keepBlocks uint64) error {
+	if uint64(len(s.rootHistory)) <= keepBlocks {
+		return nil
+	}
+
+	retained := s.rootHistory[uint64(len(s.rootHistory))-keepBlocks:]
+
+	accountRoots := make([]byteutils.Hash, 0, len(retained))
+	txRoots := make([]byteutils.Hash, 0, len(retained))
+	eventRoots := make([]byteutils.Hash, 0, len(retained))
+	consensusRoots := make([]*consensuspb.ConsensusRoot, 0, len(retained))
+	for _, r := range retained {
+		accountRoots = append(accountRoots, r.accounts)
+		txRoots = append(txRoots, r.txs)
+		eventRoots = append(eventRoots, r.events)
+		consensusRoots = append(consensusRoots, r.consensus)
+	}
+
+	if err := s.accState.Prune(accountRoots); err != nil {
+		return err
+	}
+	// txsState and eventsState are *trie.Trie values from common/trie, so
+	// unlike accState/consensusState (interfaces this package owns) Prune
+	// here has to already exist on the trie type itself.
+	if err := s.txsState.Prune(txRoots); err != nil {
+		return err
+	}
+	if err := s.eventsState.Prune(eventRoots); err != nil {
+		return err
+	}
+	if err := s.consensusState.Prune(consensusRoots); err != nil {
+		return err
+	}
+
+	s.rootHistory = retained
+	return nil
+}
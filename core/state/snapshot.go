@@ -0,0 +1,249 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"github.com/nebulasio/go-nebulas/storage"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+	"github.com/nebulasio/go-nebulas/util/logging"
+)
+
+// maxDiffLayers bounds how many recent commits' account/storage writes are
+// kept as in-memory diff layers above the on-disk flat snapshot. Beyond
+// this window a lookup falls back to the flat snapshot, and the oldest layer
+// is flattened into disk so the window keeps sliding forward.
+const maxDiffLayers = 128
+
+// Snapshot is a consistent, read-only view of account/storage state that
+// answers Account/Storage lookups in one hop instead of walking accState's
+// Merkle-Patricia trie.
+type Snapshot interface {
+	Account(addr byteutils.Hash) (Account, error)
+	Storage(addr byteutils.Hash, key byteutils.Hash) ([]byte, error)
+}
+
+// diffLayer is one commit's worth of account/storage writes, chained to
+// its parent so a lookup walks newest-to-oldest until it finds a hit.
+type diffLayer struct {
+	parent   *diffLayer
+	accounts map[string]Account
+	storage  map[string]map[string][]byte
+}
+
+func newDiffLayer(parent *diffLayer) *diffLayer {
+	return &diffLayer{
+		parent:   parent,
+		accounts: make(map[string]Account),
+		storage:  make(map[string]map[string][]byte),
+	}
+}
+
+func (l *diffLayer) depth() int {
+	depth := 0
+	for cur := l; cur != nil; cur = cur.parent {
+		depth++
+	}
+	return depth
+}
+
+// bottom returns the oldest layer still reachable from l, used to flatten
+// it into the on-disk snapshot once the stack grows past maxDiffLayers.
+func (l *diffLayer) bottom() *diffLayer {
+	cur := l
+	for cur.parent != nil {
+		cur = cur.parent
+	}
+	return cur
+}
+
+// flatSnapshot is the on-disk base of the diff-layer stack: a flat
+// accountAddr -> accountRLP (and (addr, key) -> value) key/value store,
+// refreshed in the background as layers age out of the in-memory window.
+type flatSnapshot struct {
+	db storage.Storage
+}
+
+const (
+	snapshotAccountPrefix = "snap/a/"
+	snapshotStoragePrefix = "snap/s/"
+)
+
+func newFlatSnapshot(db storage.Storage) *flatSnapshot {
+	return &flatSnapshot{db: db}
+}
+
+func (f *flatSnapshot) account(addr byteutils.Hash) ([]byte, error) {
+	return f.db.Get(append([]byte(snapshotAccountPrefix), addr...))
+}
+
+func (f *flatSnapshot) putAccount(addr byteutils.Hash, accountBytes []byte) error {
+	return f.db.Put(append([]byte(snapshotAccountPrefix), addr...), accountBytes)
+}
+
+func (f *flatSnapshot) storage(addr, key byteutils.Hash) ([]byte, error) {
+	return f.db.Get(append(append([]byte(snapshotStoragePrefix), addr...), key...))
+}
+
+func (f *flatSnapshot) putStorage(addr, key byteutils.Hash, value []byte) error {
+	return f.db.Put(append(append([]byte(snapshotStoragePrefix), addr...), key...), value)
+}
+
+// flatten persists a diff layer's writes into the on-disk flat snapshot.
+// Account objects are stored via their own ToBytes encoding, the same one
+// recordAccounts already uses for the changelog.
+func (f *flatSnapshot) flatten(layer *diffLayer) error {
+	for addr, account := range layer.accounts {
+		accountBytes, err := account.ToBytes()
+		if err != nil {
+			return err
+		}
+		if err := f.putAccount(byteutils.Hash(addr), accountBytes); err != nil {
+			return err
+		}
+	}
+	for addr, slots := range layer.storage {
+		for key, value := range slots {
+			if err := f.putStorage(byteutils.Hash(addr), byteutils.Hash(key), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotView is the Snapshot handed out to callers: the diff-layer stack
+// as of the moment Snapshot() was called, backed by the on-disk
+// flatSnapshot for anything that has aged out of the in-memory window.
+// accState is kept only to decode the raw bytes the flat snapshot stores
+// back into an Account -- never to fall back to a trie lookup, since that
+// would make this read-only view capable of mutating state.
+type snapshotView struct {
+	top      *diffLayer
+	flat     *flatSnapshot
+	accState AccountState
+}
+
+func (v *snapshotView) Account(addr byteutils.Hash) (Account, error) {
+	for l := v.top; l != nil; l = l.parent {
+		if account, ok := l.accounts[addr.String()]; ok {
+			return account, nil
+		}
+	}
+	accountBytes, err := v.flat.account(addr)
+	if err == storage.ErrKeyNotFound {
+		// Not in the diff-layer window or the flat snapshot: this address
+		// has never been dirtied, so there is nothing to return. Falling
+		// back to accState.GetOrCreateUserAccount here would create a
+		// zero-balance account as a side effect of what is documented as a
+		// read-only call, so treat a miss the same way Storage already
+		// does -- a nil Account with no error.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.accState.DecodeAccount(accountBytes)
+}
+
+func (v *snapshotView) Storage(addr, key byteutils.Hash) ([]byte, error) {
+	for l := v.top; l != nil; l = l.parent {
+		if slots, ok := l.storage[addr.String()]; ok {
+			if value, ok := slots[key.String()]; ok {
+				return value, nil
+			}
+		}
+	}
+	value, err := v.flat.storage(addr, key)
+	if err == storage.ErrKeyNotFound {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Snapshot returns a consistent read-only view over the current account
+// and storage state, answering Account/Storage in one hop for anything
+// written within the last maxDiffLayers commits.
+func (s *states) Snapshot() Snapshot {
+	return &snapshotView{top: s.diffs, flat: s.flatSnap, accState: s.accState}
+}
+
+// pushDiffLayer folds the dirty accounts (and their dirty storage slots)
+// from a just-committed block onto the diff-layer stack, trimming the
+// oldest layer into the on-disk flat snapshot once the stack exceeds
+// maxDiffLayers.
+func (s *states) pushDiffLayer() error {
+	accounts, err := s.accState.DirtyAccounts()
+	if err != nil {
+		return err
+	}
+
+	layer := newDiffLayer(s.diffs)
+	for _, account := range accounts {
+		addr := account.Address().String()
+		layer.accounts[addr] = account
+
+		dirtyStorage, err := account.DirtyStorage()
+		if err != nil {
+			return err
+		}
+		if len(dirtyStorage) > 0 {
+			layer.storage[addr] = dirtyStorage
+		}
+	}
+	s.diffs = layer
+
+	if layer.depth() > maxDiffLayers {
+		oldest := layer.bottom()
+		if err := s.flatSnap.flatten(oldest); err != nil {
+			logging.VLog().Warn("failed to flatten aged-out diff layer into flat snapshot")
+			return err
+		}
+		for cur := layer; cur.parent != nil; cur = cur.parent {
+			if cur.parent == oldest {
+				cur.parent = nil
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// regenerateSnapshot rebuilds the flat snapshot from accState's trie,
+// walking every account at the current AccountsRoot. It is the fallback
+// path for a reorg deeper than maxDiffLayers, where the in-memory diff
+// stack no longer covers the target height: the caller first calls
+// LoadAccountsRoot to the target root, drops the diff stack, and calls
+// this to bring the flat snapshot back in sync in the background.
+func (s *states) regenerateSnapshot() error {
+	s.diffs = nil
+	accounts, err := s.accState.Accounts()
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		accountBytes, err := account.ToBytes()
+		if err != nil {
+			return err
+		}
+		if err := s.flatSnap.putAccount(account.Address(), accountBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import "testing"
+
+func TestDiffLayerBottomAndDepth(t *testing.T) {
+	root := newDiffLayer(nil)
+	middle := newDiffLayer(root)
+	top := newDiffLayer(middle)
+
+	if top.depth() != 3 {
+		t.Fatalf("expected depth 3, got %d", top.depth())
+	}
+	if top.bottom() != root {
+		t.Fatal("bottom() should walk all the way to the oldest layer, not stop at its parent")
+	}
+	if root.bottom() != root {
+		t.Fatal("bottom() of the oldest layer should return itself")
+	}
+}
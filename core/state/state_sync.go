@@ -0,0 +1,148 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/nebulasio/go-nebulas/common/trie"
+	"github.com/nebulasio/go-nebulas/storage"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// ErrStateRootMismatch is returned by VerifyState when the trie rebuilt
+// from an imported stream does not hash to the expected root.
+var ErrStateRootMismatch = errors.New("imported state does not match expected root")
+
+// ExportState streams every account reachable from root as a sequence of
+// length-prefixed (key, value) records: a 4-byte big-endian key length, the
+// key, a 4-byte big-endian value length, and the value (the account's own
+// RLP encoding). This is the accounts-at-root stream a fast-syncing peer
+// requests instead of replaying every historical block.
+func (s *states) ExportState(w io.Writer, root byteutils.Hash) error {
+	snapshotTrie, err := trie.NewTrie(root, s.storage, false)
+	if err != nil {
+		return err
+	}
+
+	iter, err := snapshotTrie.Iterator(nil)
+	if err != nil && err != storage.ErrKeyNotFound {
+		return err
+	}
+	if err == storage.ErrKeyNotFound {
+		return nil
+	}
+
+	exist, err := iter.Next()
+	if err != nil {
+		return err
+	}
+	for exist {
+		if err := writeRecord(w, iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+		exist, err = iter.Next()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportState reads a stream produced by ExportState into a fresh trie and
+// returns its root. Callers are expected to run VerifyState against that
+// root (or one learned out-of-band, e.g. from a block header) before
+// calling LoadAccountsRoot to swap it in.
+func (s *states) ImportState(r io.Reader) (byteutils.Hash, error) {
+	imported, err := trie.NewTrie(nil, s.storage, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		key, value, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := imported.Put(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return imported.RootHash(), nil
+}
+
+// VerifyState rebuilds a trie from an exported stream and confirms it
+// hashes to root before the caller trusts it enough to swap in via
+// LoadAccountsRoot, the same safety check a snap-sync client runs against
+// an untrusted peer's response.
+func (s *states) VerifyState(root byteutils.Hash, r io.Reader) error {
+	rebuiltRoot, err := s.ImportState(r)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rebuiltRoot, root) {
+		return ErrStateRootMismatch
+	}
+	return nil
+}
+
+func writeRecord(w io.Writer, key, value []byte) error {
+	if err := writeLengthPrefixed(w, key); err != nil {
+		return err
+	}
+	return writeLengthPrefixed(w, value)
+}
+
+func writeLengthPrefixed(w io.Writer, data []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readRecord(r io.Reader) (key, value []byte, err error) {
+	if key, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, err
+	}
+	if value, err = readLengthPrefixed(r); err != nil {
+		return nil, nil, err
+	}
+	return key, value, nil
+}
+
+func readLengthPrefixed(r io.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteReadRecordRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeRecord(&buf, []byte("key-a"), []byte("value-a")); err != nil {
+		t.Fatalf("writeRecord failed: %v", err)
+	}
+	if err := writeRecord(&buf, []byte("key-b"), []byte("value-b")); err != nil {
+		t.Fatalf("writeRecord failed: %v", err)
+	}
+
+	key, value, err := readRecord(&buf)
+	if err != nil {
+		t.Fatalf("readRecord failed: %v", err)
+	}
+	if string(key) != "key-a" || string(value) != "value-a" {
+		t.Fatalf("got (%s, %s), want (key-a, value-a)", key, value)
+	}
+
+	key, value, err = readRecord(&buf)
+	if err != nil {
+		t.Fatalf("readRecord failed: %v", err)
+	}
+	if string(key) != "key-b" || string(value) != "value-b" {
+		t.Fatalf("got (%s, %s), want (key-b, value-b)", key, value)
+	}
+
+	if _, _, err := readRecord(&buf); err == nil {
+		t.Fatal("expected reading past the last record to return an error (io.EOF)")
+	}
+}
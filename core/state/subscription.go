@@ -0,0 +1,102 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"sync"
+
+	"github.com/nebulasio/go-nebulas/util/logging"
+)
+
+// subscriptionBufferSize bounds how many unread events a Subscription holds
+// before new matches are dropped rather than blocking Commit.
+const subscriptionBufferSize = 256
+
+// Subscription delivers events matching a FilterQuery as they are
+// committed. Callers read Events() in a loop and call Unsubscribe when
+// done.
+type Subscription struct {
+	query  FilterQuery
+	events chan *Event
+	hub    *eventHub
+}
+
+// Events returns the channel new matching events are delivered on.
+func (sub *Subscription) Events() <-chan *Event {
+	return sub.events
+}
+
+// Unsubscribe stops delivery and closes the Events() channel.
+func (sub *Subscription) Unsubscribe() {
+	sub.hub.unsubscribe(sub)
+}
+
+// eventHub fans out committed events to live subscriptions.
+type eventHub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+var defaultEventHub = &eventHub{subs: make(map[*Subscription]struct{})}
+
+// SubscribeEvents registers a live subscription for events matching query,
+// delivered as blocks are committed. It is the subscribe-side counterpart
+// to FilterEvents' historical range queries.
+func SubscribeEvents(query FilterQuery) *Subscription {
+	return defaultEventHub.subscribe(query)
+}
+
+func (h *eventHub) subscribe(query FilterQuery) *Subscription {
+	sub := &Subscription{
+		query:  query,
+		events: make(chan *Event, subscriptionBufferSize),
+		hub:    h,
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *eventHub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+	h.mu.Unlock()
+}
+
+// publish fans event out to every subscription whose filter matches. Sends
+// are non-blocking so a slow subscriber drops events instead of stalling
+// block commit.
+func (h *eventHub) publish(event *Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.query.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			logging.VLog().Warn("event subscription buffer full, dropping event")
+		}
+	}
+}
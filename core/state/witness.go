@@ -0,0 +1,336 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/nebulasio/go-nebulas/consensus/pb"
+	"github.com/nebulasio/go-nebulas/crypto/hash"
+	"github.com/nebulasio/go-nebulas/storage"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// Witness is the set of Merkle multi-proofs for every trie node a
+// TxWorldState touched while executing one transaction: account reads,
+// contract storage reads, tx lookups, and event fetches. A verifier that
+// does not hold the full state can populate an in-memory trie from these
+// proofs, re-run the transaction against it, and confirm the resulting
+// roots match -- the same approach Ethereum's stateless clients and
+// fraud-proof verifiers use.
+type Witness struct {
+	AccountProofs [][][]byte `json:"accounts,omitempty"`
+	StorageProofs [][][]byte `json:"storage,omitempty"`
+	TxProofs      [][][]byte `json:"txs,omitempty"`
+	EventProofs   [][][]byte `json:"events,omitempty"`
+}
+
+// witnessCollector accumulates proofs as a txWorldState's read methods are
+// called, deduplicating repeat reads of the same key.
+type witnessCollector struct {
+	touchedAccounts map[string]bool
+	touchedStorage  map[string]bool
+	touchedTxs      map[string]bool
+	touchedEvents   map[string]bool
+
+	result *Witness
+}
+
+func newWitnessCollector() *witnessCollector {
+	return &witnessCollector{
+		touchedAccounts: make(map[string]bool),
+		touchedStorage:  make(map[string]bool),
+		touchedTxs:      make(map[string]bool),
+		touchedEvents:   make(map[string]bool),
+		result:          &Witness{},
+	}
+}
+
+func (ts *txWorldState) touchAccount(addr byteutils.Hash) error {
+	key := addr.String()
+	if ts.witness.touchedAccounts[key] {
+		return nil
+	}
+	proof, err := ts.accState.Prove(addr)
+	if err != nil {
+		return err
+	}
+	ts.witness.touchedAccounts[key] = true
+	ts.witness.result.AccountProofs = append(ts.witness.result.AccountProofs, proof)
+	return nil
+}
+
+// TouchStorage records a contract storage read for the witness. It is
+// called from witnessedAccount.GetContractState, the one place every
+// contract storage read passes through once GetContractAccount has handed
+// out a witnessed Account.
+func (ts *txWorldState) TouchStorage(addr, key byteutils.Hash) error {
+	combined := addr.String() + "/" + key.String()
+	if ts.witness.touchedStorage[combined] {
+		return nil
+	}
+	proof, err := ts.accState.ProveStorage(addr, key)
+	if err != nil {
+		return err
+	}
+	ts.witness.touchedStorage[combined] = true
+	ts.witness.result.StorageProofs = append(ts.witness.result.StorageProofs, proof)
+	return nil
+}
+
+func (ts *txWorldState) touchTx(txHash byteutils.Hash) error {
+	key := txHash.String()
+	if ts.witness.touchedTxs[key] {
+		return nil
+	}
+	proof, err := ts.txsState.Prove(txHash)
+	if err != nil {
+		return err
+	}
+	ts.witness.touchedTxs[key] = true
+	ts.witness.result.TxProofs = append(ts.witness.result.TxProofs, proof)
+	return nil
+}
+
+func (ts *txWorldState) touchEvents(txHash byteutils.Hash) error {
+	key := txHash.String()
+	if ts.witness.touchedEvents[key] {
+		return nil
+	}
+	proof, err := ts.eventsState.Prove(txHash)
+	if err != nil {
+		return err
+	}
+	ts.witness.touchedEvents[key] = true
+	ts.witness.result.EventProofs = append(ts.witness.result.EventProofs, proof)
+	return nil
+}
+
+// GetOrCreateUserAccount records the account's Merkle proof before
+// returning it, so Witness() can reconstruct this read for a verifier.
+func (ts *txWorldState) GetOrCreateUserAccount(addr byteutils.Hash) (Account, error) {
+	account, err := ts.states.GetOrCreateUserAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.touchAccount(addr); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetContractAccount records the account's Merkle proof before returning
+// it, the same as GetOrCreateUserAccount, and wraps the result so that
+// every contract storage read made through it is recorded into the
+// witness too -- this is the read path TouchStorage hooks into, since
+// storage reads only ever happen through the Account a contract
+// execution was handed.
+func (ts *txWorldState) GetContractAccount(addr byteutils.Hash) (Account, error) {
+	account, err := ts.states.GetContractAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.touchAccount(addr); err != nil {
+		return nil, err
+	}
+	return &witnessedAccount{Account: account, ts: ts}, nil
+}
+
+// witnessedAccount decorates an Account so that every contract storage
+// read made through it is recorded into the owning txWorldState's witness,
+// without the (native or NVM) contract execution path needing to know the
+// witness subsystem exists.
+type witnessedAccount struct {
+	Account
+	ts *txWorldState
+}
+
+func (a *witnessedAccount) GetContractState(key []byte) ([]byte, error) {
+	value, err := a.Account.GetContractState(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ts.TouchStorage(a.Account.Address(), key); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GetTx records the transaction's Merkle proof before returning it.
+func (ts *txWorldState) GetTx(txHash byteutils.Hash) ([]byte, error) {
+	txBytes, err := ts.states.GetTx(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.touchTx(txHash); err != nil {
+		return nil, err
+	}
+	return txBytes, nil
+}
+
+// FetchEvents records the events' Merkle proof before returning them.
+func (ts *txWorldState) FetchEvents(txHash byteutils.Hash) ([]*Event, error) {
+	events, err := ts.states.FetchEvents(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.touchEvents(txHash); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Witness encodes every trie node this TxWorldState's execution touched.
+func (ts *txWorldState) Witness() ([]byte, error) {
+	return json.Marshal(ts.witness.result)
+}
+
+// WitnessRoots bundles the four roots a block commits to, the same four
+// values AccountsRoot/TxsRoot/EventsRoot/ConsensusRoot expose individually
+// on WorldState, so VerifyBlockWitness can be told a block's pre- and
+// post-execution state without needing the full WorldState around it.
+type WitnessRoots struct {
+	AccountsRoot  byteutils.Hash
+	TxsRoot       byteutils.Hash
+	EventsRoot    byteutils.Hash
+	ConsensusRoot *consensuspb.ConsensusRoot
+}
+
+// VerifyBlockWitness re-executes txs against an in-memory trie populated
+// solely from witness, starting from preRoots, and confirms the resulting
+// roots match postRoots. It lets a light client, rollup fraud-proof
+// verifier, or portal-style stateless peer check a block without holding
+// the full state, using only what the block producer claims it read.
+// consensus is the caller's own consensus engine, needed only to decode
+// ConsensusRoot the same way a full WorldState would.
+func VerifyBlockWitness(consensus Consensus, preRoots *WitnessRoots, witness []byte, txs []Executable, postRoots *WitnessRoots) error {
+	decoded := new(Witness)
+	if err := json.Unmarshal(witness, decoded); err != nil {
+		return err
+	}
+
+	mem, err := newWitnessStorage(decoded)
+	if err != nil {
+		return err
+	}
+	ws, err := NewWorldState(consensus, mem)
+	if err != nil {
+		return err
+	}
+	if err := ws.LoadAccountsRoot(preRoots.AccountsRoot); err != nil {
+		return err
+	}
+	if err := ws.LoadTxsRoot(preRoots.TxsRoot); err != nil {
+		return err
+	}
+	if err := ws.LoadEventsRoot(preRoots.EventsRoot); err != nil {
+		return err
+	}
+	if err := ws.LoadConsensusRoot(preRoots.ConsensusRoot); err != nil {
+		return err
+	}
+
+	if err := ws.Begin(); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		txid := tx.TxHash().String()
+		txState, err := ws.Prepare(txid)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Execute(txState); err != nil {
+			return err
+		}
+		if _, err := ws.CheckAndUpdate(txid); err != nil {
+			return err
+		}
+		if err := ws.Close(txid); err != nil {
+			return err
+		}
+	}
+	if err := ws.Commit(); err != nil {
+		return err
+	}
+
+	return compareRoots(ws, postRoots)
+}
+
+// newWitnessStorage builds a MemoryStorage containing only the trie nodes
+// named in witness, keyed by their own content hash -- the same
+// content-addressing the on-disk trie storage already uses. A read for any
+// node outside this set simply misses, which is exactly the point: a
+// stateless verifier must fail closed if the block producer's witness
+// turns out to be incomplete.
+func newWitnessStorage(witness *Witness) (storage.Storage, error) {
+	mem, err := storage.NewMemoryStorage()
+	if err != nil {
+		return nil, err
+	}
+	proofSets := [][][][]byte{
+		witness.AccountProofs,
+		witness.StorageProofs,
+		witness.TxProofs,
+		witness.EventProofs,
+	}
+	for _, proofs := range proofSets {
+		for _, proof := range proofs {
+			for _, node := range proof {
+				if err := mem.Put(hash.Sha3256(node), node); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return mem, nil
+}
+
+func compareRoots(ws WorldState, postRoots *WitnessRoots) error {
+	accountsRoot, err := ws.AccountsRoot()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(accountsRoot, postRoots.AccountsRoot) {
+		return ErrStateRootMismatch
+	}
+	txsRoot, err := ws.TxsRoot()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(txsRoot, postRoots.TxsRoot) {
+		return ErrStateRootMismatch
+	}
+	eventsRoot, err := ws.EventsRoot()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(eventsRoot, postRoots.EventsRoot) {
+		return ErrStateRootMismatch
+	}
+	consensusRoot, err := ws.ConsensusRoot()
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(consensusRoot, postRoots.ConsensusRoot) {
+		return ErrStateRootMismatch
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package state
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nebulasio/go-nebulas/crypto/hash"
+)
+
+func TestNewWitnessStorageLoadsEveryProofNode(t *testing.T) {
+	accountNode := []byte("account-node")
+	storageNode := []byte("storage-node")
+
+	witness := &Witness{
+		AccountProofs: [][][]byte{{accountNode}},
+		StorageProofs: [][][]byte{{storageNode}},
+	}
+
+	mem, err := newWitnessStorage(witness)
+	if err != nil {
+		t.Fatalf("newWitnessStorage failed: %v", err)
+	}
+
+	got, err := mem.Get(hash.Sha3256(accountNode))
+	if err != nil {
+		t.Fatalf("account node missing from witness storage: %v", err)
+	}
+	if !bytes.Equal(got, accountNode) {
+		t.Fatal("account node round-tripped with the wrong bytes")
+	}
+
+	got, err = mem.Get(hash.Sha3256(storageNode))
+	if err != nil {
+		t.Fatalf("storage node missing from witness storage: %v", err)
+	}
+	if !bytes.Equal(got, storageNode) {
+		t.Fatal("storage node round-tripped with the wrong bytes")
+	}
+}
@@ -65,6 +65,12 @@ type states struct {
 
 	gasConsumed map[string]*util.Uint128
 	events      map[string][]*Event
+	bloom       *EventBloom
+
+	flatSnap *flatSnapshot
+	diffs    *diffLayer
+
+	rootHistory []rootSet
 }
 
 func newStates(consensus Consensus, stor storage.Storage) (*states, error) {
@@ -108,6 +114,8 @@ func newStates(consensus Consensus, stor storage.Storage) (*states, error) {
 
 		gasConsumed: make(map[string]*util.Uint128),
 		events:      make(map[string][]*Event),
+		bloom:       new(EventBloom),
+		flatSnap:    newFlatSnapshot(stor),
 	}, nil
 }
 
@@ -131,6 +139,8 @@ func (s *states) Replay(done *states) error {
 		return err
 	}
 
+	s.bloom.Or(done.bloom)
+
 	// replay gasconsumed
 	for from, gas := range done.gasConsumed {
 		consumed, ok := s.gasConsumed[from]
@@ -173,8 +183,20 @@ func (s *states) ReplayEvent(done *states) error {
 		if err != nil {
 			return err
 		}
+
+		// RecordEvent indexes a tx-local event by topic so FilterEvents can
+		// look it up without a full scan; that index has to be replayed into
+		// the committed eventsState the same way, or a topic-filtered query
+		// against a real, committed block always comes up empty.
+		for _, topic := range event.indexedTopics() {
+			s.bloom.Add(topic)
+			indexKey := topicIndexKey(topic, key)
+			if _, err := s.eventsState.Put(indexKey, bytes); err != nil {
+				return err
+			}
+		}
 	}
-	//s.events[tx] = done.events[tx]
+	s.events[tx] = append(s.events[tx], done.events[tx]...)
 	done.events = make(map[string][]*Event)
 	return nil
 }
@@ -220,6 +242,9 @@ func (s *states) Clone() (WorldState, error) {
 
 		gasConsumed: make(map[string]*util.Uint128),
 		events:      make(map[string][]*Event),
+		bloom:       new(EventBloom),
+		flatSnap:    s.flatSnap,
+		diffs:       s.diffs,
 	}, nil
 }
 
@@ -237,8 +262,20 @@ func (s *states) Commit() error {
 	if err := s.storage.Commit(); err != nil {
 		return err
 	}
+	for _, events := range s.events {
+		for _, event := range events {
+			defaultEventHub.publish(event)
+		}
+	}
 	s.events = make(map[string][]*Event)
 	s.gasConsumed = make(map[string]*util.Uint128)
+	s.bloom = new(EventBloom)
+	if err := s.pushDiffLayer(); err != nil {
+		return err
+	}
+	if err := s.recordRoots(); err != nil {
+		return err
+	}
 	s.accState.CommitAccounts()
 	return nil
 }
@@ -252,6 +289,10 @@ func (s *states) RollBack() error {
 	}
 	s.events = make(map[string][]*Event)
 	s.gasConsumed = make(map[string]*util.Uint128)
+	s.bloom = new(EventBloom)
+	if s.diffs != nil {
+		s.diffs = s.diffs.parent
+	}
 	s.accState.RollBackAccounts()
 	return nil
 }
@@ -297,6 +338,9 @@ func (s *states) Prepare(txid interface{}) (TxWorldState, error) {
 
 		gasConsumed: make(map[string]*util.Uint128),
 		events:      make(map[string][]*Event),
+		bloom:       new(EventBloom),
+		flatSnap:    s.flatSnap,
+		diffs:       s.diffs,
 	}, nil
 }
 
@@ -442,9 +486,32 @@ func (s *states) RecordEvent(txHash byteutils.Hash, event *Event) error {
 		logging.VLog().Info("REE 12")
 		return err
 	}
+
+	for _, topic := range event.indexedTopics() {
+		s.bloom.Add(topic)
+		indexKey := topicIndexKey(topic, key)
+		if _, err := s.eventsState.Put(indexKey, bytes); err != nil {
+			logging.VLog().Info("REE 13")
+			return err
+		}
+		if err := s.changelog.Put(indexKey, bytes); err != nil {
+			logging.VLog().Info("REE 14")
+			return err
+		}
+	}
 	return nil
 }
 
+// GetBloom returns the EventBloom accumulated from every event recorded
+// since the last GetBloom/Commit/RollBack, then resets it, mirroring
+// GetGas. The caller folds the result into the block header so that
+// FilterEvents can skip the whole block when a query's topics miss.
+func (s *states) GetBloom() *EventBloom {
+	bloom := s.bloom
+	s.bloom = new(EventBloom)
+	return bloom
+}
+
 func (s *states) fetchCacheEvents(txHash byteutils.Hash) ([]*Event, error) {
 	txevents, ok := s.events[txHash.String()]
 	if !ok {
@@ -631,7 +698,8 @@ func (ws *worldState) Dispose() {
 
 type txWorldState struct {
 	*states
-	txid interface{}
+	txid    interface{}
+	witness *witnessCollector
 }
 
 func (ws *worldState) Prepare(txid interface{}) (TxWorldState, error) {
@@ -644,13 +712,21 @@ func (ws *worldState) Prepare(txid interface{}) (TxWorldState, error) {
 		return nil, err
 	}
 	txState := &txWorldState{
-		states: s.(*states),
-		txid:   txid,
+		states:  s.(*states),
+		txid:    txid,
+		witness: newWitnessCollector(),
 	}
 	ws.txStates.Store(txid, txState)
 	return txState, nil
 }
 
+// CheckAndUpdate validates txid's speculative changelog/storage writes
+// against the current global version and reports which other in-flight
+// transactions it conflicts with. It does not merge txid's effects into
+// ws.states -- a caller scheduling several transactions concurrently has to
+// be able to reject this result (via Reset) after seeing the conflict list,
+// and a merge here would already be irreversible by then. The merge happens
+// in Close, once the caller has decided to keep the result.
 func (ws *worldState) CheckAndUpdate(txid interface{}) ([]interface{}, error) {
 	state, ok := ws.txStates.Load(txid)
 	if !ok {
@@ -662,10 +738,6 @@ func (ws *worldState) CheckAndUpdate(txid interface{}) ([]interface{}, error) {
 		logging.VLog().Info("CUE 1")
 		return nil, err
 	}
-	if err := ws.states.Replay(txWorldState.states); err != nil {
-		logging.VLog().Info("CUE 2")
-		return nil, err
-	}
 
 	return dependencies, nil
 }
@@ -680,6 +752,11 @@ func (ws *worldState) Reset(txid interface{}) error {
 		logging.VLog().Info("RSE 1")
 		return err
 	}
+	// A reset transaction is done with its speculative state for good --
+	// callers re-queue it for a later retry, which starts over with a
+	// fresh Prepare, so the stale entry must not linger in txStates the
+	// way Close's already doesn't.
+	ws.txStates.Delete(txid)
 	return nil
 }
 
@@ -689,10 +766,17 @@ func (ws *worldState) Close(txid interface{}) error {
 		return ErrCannotUpdateTxStateBeforePrepare
 	}
 	txWorldState := state.(*txWorldState)
-	if err := txWorldState.Close(txid); err != nil {
+	// The merge into ws.states happens here rather than in CheckAndUpdate:
+	// Close is the point a caller has committed to keeping txid's result, so
+	// this is the only place the merge is guaranteed not to need undoing.
+	if err := ws.states.Replay(txWorldState.states); err != nil {
 		logging.VLog().Info("CSE 1")
 		return err
 	}
+	if err := txWorldState.Close(txid); err != nil {
+		logging.VLog().Info("CSE 2")
+		return err
+	}
 	ws.txStates.Delete(txid)
 	return nil
 }
@@ -0,0 +1,80 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+// Package graphql exposes a read-only GraphQL endpoint backed directly by
+// core/state.WorldState, as an alternative developer surface to the
+// existing per-endpoint gRPC/HTTP API. The schema mirrors EIP-1767.
+package graphql
+
+import (
+	"github.com/nebulasio/go-nebulas/core/state"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// Block is the minimal view of a chain block the resolvers need. The node's
+// blockchain type already satisfies this; it is declared here so this
+// package does not import core and create a dependency cycle.
+type Block interface {
+	Height() uint64
+	Hash() []byte
+	ParentHash() []byte
+	Timestamp() int64
+	WorldState() state.WorldState
+	Transactions() []byteutils.Hash
+}
+
+// BlockReader looks up blocks by number or hash, the two root fields every
+// other query hangs off of.
+type BlockReader interface {
+	BlockByNumber(number uint64) (Block, error)
+	BlockByHash(hash []byte) (Block, error)
+	TailBlock() (Block, error)
+}
+
+// cloneAt returns a WorldState positioned at the given block's committed
+// roots, so resolvers can read account/tx/event data without mutating the
+// live chain state other requests are using.
+func cloneAt(b Block) (state.WorldState, error) {
+	ws := b.WorldState()
+	cloned, err := ws.Clone()
+	if err != nil {
+		return nil, err
+	}
+	accountsRoot, err := ws.AccountsRoot()
+	if err != nil {
+		return nil, err
+	}
+	if err := cloned.LoadAccountsRoot(accountsRoot); err != nil {
+		return nil, err
+	}
+	txsRoot, err := ws.TxsRoot()
+	if err != nil {
+		return nil, err
+	}
+	if err := cloned.LoadTxsRoot(txsRoot); err != nil {
+		return nil, err
+	}
+	eventsRoot, err := ws.EventsRoot()
+	if err != nil {
+		return nil, err
+	}
+	if err := cloned.LoadEventsRoot(eventsRoot); err != nil {
+		return nil, err
+	}
+	return cloned, nil
+}
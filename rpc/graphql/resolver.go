@@ -0,0 +1,238 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package graphql
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/nebulasio/go-nebulas/core/state"
+	"github.com/nebulasio/go-nebulas/util/byteutils"
+)
+
+// ErrBlockNotFound is returned when neither number nor hash resolves to a
+// known block.
+var ErrBlockNotFound = errors.New("graphql: block not found")
+
+// Resolver wires GraphQL field resolution to a BlockReader. It holds no
+// state of its own beyond that reader, so a single Resolver can be shared
+// across concurrent requests.
+type Resolver struct {
+	reader BlockReader
+}
+
+// NewResolver builds a Resolver over reader.
+func NewResolver(reader BlockReader) *Resolver {
+	return &Resolver{reader: reader}
+}
+
+// blockContext is the per-request Source handed to every field under
+// `block`: the resolved Block plus a WorldState cloned and loaded to that
+// block's roots, so account/transaction/event resolvers never touch the
+// live chain tip.
+type blockContext struct {
+	block Block
+	ws    state.WorldState
+}
+
+func (r *Resolver) resolveBlock(p graphql.ResolveParams) (interface{}, error) {
+	var (
+		block Block
+		err   error
+	)
+	switch {
+	case p.Args["hash"] != nil:
+		hash, decodeErr := hex.DecodeString(p.Args["hash"].(string))
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		block, err = r.reader.BlockByHash(hash)
+	case p.Args["number"] != nil:
+		number, parseErr := strconv.ParseUint(p.Args["number"].(string), 10, 64)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		block, err = r.reader.BlockByNumber(number)
+	default:
+		block, err = r.reader.TailBlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	ws, err := cloneAt(block)
+	if err != nil {
+		return nil, err
+	}
+	return &blockContext{block: block, ws: ws}, nil
+}
+
+func resolveBlockNumber(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	return strconv.FormatUint(ctx.block.Height(), 10), nil
+}
+
+func resolveBlockHash(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	return byteutils.Hash(ctx.block.Hash()).String(), nil
+}
+
+func resolveBlockParentHash(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	return byteutils.Hash(ctx.block.ParentHash()).String(), nil
+}
+
+func resolveBlockTimestamp(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	return strconv.FormatInt(ctx.block.Timestamp(), 10), nil
+}
+
+func resolveBlockAccountsRoot(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	root, err := ctx.ws.AccountsRoot()
+	if err != nil {
+		return nil, err
+	}
+	return root.String(), nil
+}
+
+func resolveBlockTxsRoot(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	root, err := ctx.ws.TxsRoot()
+	if err != nil {
+		return nil, err
+	}
+	return root.String(), nil
+}
+
+func resolveBlockEventsRoot(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	root, err := ctx.ws.EventsRoot()
+	if err != nil {
+		return nil, err
+	}
+	return root.String(), nil
+}
+
+func resolveBlockConsensusRoot(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	root, err := ctx.ws.ConsensusRoot()
+	if err != nil {
+		return nil, err
+	}
+	return root.String(), nil
+}
+
+func resolveAccount(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	addr, err := byteutils.FromHex(p.Args["address"].(string))
+	if err != nil {
+		return nil, err
+	}
+	return ctx.ws.GetOrCreateUserAccount(addr)
+}
+
+func resolveAccountAddress(p graphql.ResolveParams) (interface{}, error) {
+	account := p.Source.(state.Account)
+	return account.Address().String(), nil
+}
+
+func resolveAccountBalance(p graphql.ResolveParams) (interface{}, error) {
+	account := p.Source.(state.Account)
+	return account.Balance().String(), nil
+}
+
+func resolveAccountNonce(p graphql.ResolveParams) (interface{}, error) {
+	account := p.Source.(state.Account)
+	return strconv.FormatUint(account.Nonce(), 10), nil
+}
+
+func resolveTransactionHash(p graphql.ResolveParams) (interface{}, error) {
+	tx := p.Source.(*transactionContext)
+	return tx.hash.String(), nil
+}
+
+func resolveAccounts(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	return ctx.ws.Accounts()
+}
+
+func resolveDynasty(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	dynasty, err := ctx.ws.Dynasty()
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(dynasty))
+	for i, addr := range dynasty {
+		addrs[i] = addr.String()
+	}
+	return addrs, nil
+}
+
+// transactionContext carries the resolved tx bytes plus the WorldState they
+// were read from, so the nested `events` field can call FetchEvents without
+// re-resolving the block.
+type transactionContext struct {
+	hash byteutils.Hash
+	ws   state.WorldState
+}
+
+func resolveTransaction(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	hash, err := byteutils.FromHex(p.Args["hash"].(string))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ctx.ws.GetTx(hash); err != nil {
+		return nil, err
+	}
+	return &transactionContext{hash: hash, ws: ctx.ws}, nil
+}
+
+func resolveTransactions(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*blockContext)
+	hashes := ctx.block.Transactions()
+	if first, ok := p.Args["first"].(int); ok && first < len(hashes) {
+		hashes = hashes[:first]
+	}
+	txs := make([]*transactionContext, len(hashes))
+	for i, hash := range hashes {
+		txs[i] = &transactionContext{hash: hash, ws: ctx.ws}
+	}
+	return txs, nil
+}
+
+func resolveTransactionEvents(p graphql.ResolveParams) (interface{}, error) {
+	ctx := p.Source.(*transactionContext)
+	events, err := ctx.ws.FetchEvents(ctx.hash)
+	if err != nil {
+		return nil, err
+	}
+	if first, ok := p.Args["first"].(int); ok && first < len(events) {
+		events = events[:first]
+	}
+	return events, nil
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package graphql
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+var eventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Event",
+	Fields: graphql.Fields{
+		"topic": &graphql.Field{Type: graphql.String},
+		"data":  &graphql.Field{Type: graphql.String},
+	},
+})
+
+var accountType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Account",
+	Fields: graphql.Fields{
+		"address": &graphql.Field{Type: graphql.String, Resolve: resolveAccountAddress},
+		"balance": &graphql.Field{Type: graphql.String, Resolve: resolveAccountBalance},
+		"nonce":   &graphql.Field{Type: graphql.String, Resolve: resolveAccountNonce},
+	},
+})
+
+var transactionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Transaction",
+	Fields: graphql.Fields{
+		"hash": &graphql.Field{Type: graphql.String, Resolve: resolveTransactionHash},
+		"events": &graphql.Field{
+			Type: graphql.NewList(eventType),
+			Args: graphql.FieldConfigArgument{
+				"first": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: resolveTransactionEvents,
+		},
+	},
+})
+
+var blockType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Block",
+	Fields: graphql.Fields{
+		"number":        &graphql.Field{Type: graphql.String, Resolve: resolveBlockNumber},
+		"hash":          &graphql.Field{Type: graphql.String, Resolve: resolveBlockHash},
+		"parentHash":    &graphql.Field{Type: graphql.String, Resolve: resolveBlockParentHash},
+		"timestamp":     &graphql.Field{Type: graphql.String, Resolve: resolveBlockTimestamp},
+		"accountsRoot":  &graphql.Field{Type: graphql.String, Resolve: resolveBlockAccountsRoot},
+		"txsRoot":       &graphql.Field{Type: graphql.String, Resolve: resolveBlockTxsRoot},
+		"eventsRoot":    &graphql.Field{Type: graphql.String, Resolve: resolveBlockEventsRoot},
+		"consensusRoot": &graphql.Field{Type: graphql.String, Resolve: resolveBlockConsensusRoot},
+		"account": &graphql.Field{
+			Type: accountType,
+			Args: graphql.FieldConfigArgument{
+				"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolveAccount,
+		},
+		"transaction": &graphql.Field{
+			Type: transactionType,
+			Args: graphql.FieldConfigArgument{
+				"hash": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolveTransaction,
+		},
+		"transactions": &graphql.Field{
+			Type: graphql.NewList(transactionType),
+			Args: graphql.FieldConfigArgument{
+				"first": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: resolveTransactions,
+		},
+		"accounts": &graphql.Field{
+			Type: graphql.NewList(accountType),
+			Resolve: resolveAccounts,
+		},
+		"dynasty": &graphql.Field{
+			Type:    graphql.NewList(graphql.String),
+			Resolve: resolveDynasty,
+		},
+	},
+})
+
+// NewSchema builds the GraphQL schema served by Server, rooted at the
+// `block` field the way EIP-1767 roots a client's query at a block.
+func NewSchema(resolver *Resolver) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": &graphql.Field{
+				Type: blockType,
+				Args: graphql.FieldConfigArgument{
+					"number": &graphql.ArgumentConfig{Type: graphql.String},
+					"hash":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolver.resolveBlock,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
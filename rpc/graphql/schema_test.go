@@ -0,0 +1,49 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNewSchemaBuilds guards against a typo or duplicate field breaking
+// schema construction.
+func TestNewSchemaBuilds(t *testing.T) {
+	if _, err := NewSchema(NewResolver(nil)); err != nil {
+		t.Fatalf("NewSchema failed: %v", err)
+	}
+}
+
+// TestBlockTypeHasTransactionsField guards against the schema regressing to
+// only exposing the singular transaction(hash:) field: the query examples
+// this package is built around -- transactions(first:50) { hash events
+// {...} } -- need a list field to resolve against.
+func TestBlockTypeHasTransactionsField(t *testing.T) {
+	field := blockType.Fields()["transactions"]
+	if field == nil {
+		t.Fatal("expected blockType to expose a transactions field")
+	}
+	if !strings.Contains(field.Type.String(), "Transaction") {
+		t.Fatalf("expected transactions field to return Transaction values, got %s", field.Type.String())
+	}
+	if len(field.Args) != 1 || field.Args[0].Name() != "first" {
+		t.Fatal("expected transactions field to take a first argument")
+	}
+}
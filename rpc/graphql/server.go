@@ -0,0 +1,97 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/nebulasio/go-nebulas/util/logging"
+)
+
+// Server is a read-only HTTP handler serving GraphQL queries against a
+// BlockReader-backed schema. It is deliberately minimal: one handler, no
+// auth, no mutations -- this is a query surface, not a write path.
+type Server struct {
+	schema graphql.Schema
+}
+
+// NewServer builds a Server resolving queries through resolver.
+func NewServer(resolver *Resolver) (*Server, error) {
+	schema, err := NewSchema(resolver)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{schema: schema}, nil
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP accepts either a single GraphQL request object or a JSON array
+// of them, so dashboards can batch several queries (e.g. one per panel)
+// into a single round-trip instead of opening a connection per query.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "graphql: only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "graphql: failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var batch []graphQLRequest
+	var single graphQLRequest
+	if err := json.Unmarshal(body, &single); err == nil && single.Query != "" {
+		batch = []graphQLRequest{single}
+	} else if err := json.Unmarshal(body, &batch); err != nil {
+		http.Error(w, "graphql: malformed request body", http.StatusBadRequest)
+		return
+	}
+
+	results := make([]*graphql.Result, len(batch))
+	for i, req := range batch {
+		results[i] = graphql.Do(graphql.Params{
+			Schema:         s.schema,
+			RequestString:  req.Query,
+			OperationName:  req.OperationName,
+			VariableValues: req.Variables,
+			Context:        context.Background(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var payload interface{} = results
+	if len(results) == 1 {
+		payload = results[0]
+	}
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		logging.VLog().Error("graphql: failed to encode response")
+	}
+}